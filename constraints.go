@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// constraintPrefix marks a gogen directive comment declaring a requirement
+// on a placeholder type, e.g.:
+//
+//	// gogen:constraint _typeKey_ comparable
+//	// gogen:constraint _typeValue_ implements io.Reader
+//	// gogen:constraint _typeValue_ numeric
+const constraintPrefix = "gogen:constraint "
+
+// constraint is a single requirement declared on a placeholder, together
+// with the position of the directive in the template source.
+type constraint struct {
+	placeholder string // e.g. "_typeKey_"
+	kind        string // "comparable", "numeric", or "implements"
+	iface       string // qualified interface name, only set for "implements"
+	pos         token.Pos
+}
+
+// parseConstraints collects gogen:constraint directives out of the
+// template's comments, before rewriteComments rewrites placeholder tokens
+// inside comment text. A malformed directive is reported as an error
+// rather than aborting the process, since instantiatePackage may be one
+// of many running concurrently under -manifest.
+func parseConstraints(fset *token.FileSet, comments []*ast.CommentGroup) ([]constraint, error) {
+	var cs []constraint
+	for _, group := range comments {
+		for _, c := range group.List {
+			text := strings.TrimSpace(strings.TrimPrefix(c.Text, "//"))
+			if !strings.HasPrefix(text, constraintPrefix) {
+				continue
+			}
+
+			fields := strings.Fields(strings.TrimPrefix(text, constraintPrefix))
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("%s: malformed directive %q", fset.Position(c.Pos()), c.Text)
+			}
+
+			switch fields[1] {
+			case "comparable", "numeric":
+				cs = append(cs, constraint{placeholder: fields[0], kind: fields[1], pos: c.Pos()})
+			case "implements":
+				if len(fields) < 3 {
+					return nil, fmt.Errorf("%s: %q requires an interface name", fset.Position(c.Pos()), text)
+				}
+				cs = append(cs, constraint{placeholder: fields[0], kind: "implements", iface: fields[2], pos: c.Pos()})
+			default:
+				return nil, fmt.Errorf("%s: unknown constraint kind %q", fset.Position(c.Pos()), fields[1])
+			}
+		}
+	}
+	return cs, nil
+}
+
+// checkConstraints resolves the concrete type substituted for each
+// constrained placeholder -- loading it with golang.org/x/tools/go/packages
+// when it names a type in another package -- and rejects mappings that
+// violate a declared constraint, pointing at both the template's directive
+// and the instantiation's mapping.
+func checkConstraints(fset *token.FileSet, cs []constraint, mapping map[string]string) error {
+	for _, c := range cs {
+		concrete := mapping[c.placeholder]
+		if concrete == "" {
+			continue // this run doesn't substitute the constrained placeholder
+		}
+
+		typ, err := resolveType(concrete)
+		if err != nil {
+			return fmt.Errorf("%s: gogen:constraint %s %s: resolving %q: %v",
+				fset.Position(c.pos), c.placeholder, c.kind, concrete, err)
+		}
+
+		switch c.kind {
+		case "comparable":
+			if !types.Comparable(typ) {
+				return fmt.Errorf("%s: gogen:constraint %s comparable: %s is not comparable",
+					fset.Position(c.pos), c.placeholder, concrete)
+			}
+
+		case "numeric":
+			basic, ok := typ.Underlying().(*types.Basic)
+			if !ok || basic.Info()&types.IsNumeric == 0 {
+				return fmt.Errorf("%s: gogen:constraint %s numeric: %s is not numeric",
+					fset.Position(c.pos), c.placeholder, concrete)
+			}
+
+		case "implements":
+			iface, err := resolveInterface(c.iface)
+			if err != nil {
+				return fmt.Errorf("%s: gogen:constraint %s implements %s: %v",
+					fset.Position(c.pos), c.placeholder, c.iface, err)
+			}
+			if !types.Implements(typ, iface) && !types.Implements(types.NewPointer(typ), iface) {
+				return fmt.Errorf("%s: gogen:constraint %s implements %s: %s does not implement it",
+					fset.Position(c.pos), c.placeholder, c.iface, concrete)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveType returns the types.Type named by name, which is either a
+// predeclared type name (int, string, ...) or a qualified name such as
+// "github.com/foo/bar.Baz".
+func resolveType(name string) (types.Type, error) {
+	pkgPath, ident := splitQualified(name)
+	if pkgPath == "" {
+		tv, err := types.Eval(token.NewFileSet(), nil, token.NoPos, name)
+		if err != nil {
+			return nil, err
+		}
+		return tv.Type, nil
+	}
+
+	obj, err := lookupPackageObject(pkgPath, ident)
+	if err != nil {
+		return nil, err
+	}
+	return obj.Type(), nil
+}
+
+// resolveInterface returns the named interface type, e.g. for "io.Reader".
+func resolveInterface(name string) (*types.Interface, error) {
+	pkgPath, ident := splitQualified(name)
+	if pkgPath == "" {
+		return nil, fmt.Errorf("interface name must be qualified, e.g. io.Reader")
+	}
+
+	obj, err := lookupPackageObject(pkgPath, ident)
+	if err != nil {
+		return nil, err
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an interface", name)
+	}
+	return iface, nil
+}
+
+func splitQualified(name string) (pkgPath, ident string) {
+	i := strings.LastIndex(name, ".")
+	if i < 0 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
+
+func lookupPackageObject(pkgPath, ident string) (types.Object, error) {
+	pkg, err := loadPackage(pkgPath, packages.NeedTypes|packages.NeedName)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := pkg.Types.Scope().Lookup(ident)
+	if obj == nil {
+		return nil, fmt.Errorf("%s.%s not found", pkgPath, ident)
+	}
+	return obj, nil
+}
+
+// packageName returns a package's declared name, which need not match the
+// last segment of its import path -- e.g. "gopkg.in/yaml.v2" declares
+// package "yaml" -- so that code referencing it qualifies with the name
+// the package actually compiles under.
+func packageName(pkgPath string) (string, error) {
+	pkg, err := loadPackage(pkgPath, packages.NeedName)
+	if err != nil {
+		return "", err
+	}
+	return pkg.Name, nil
+}
+
+func loadPackage(pkgPath string, mode packages.LoadMode) (*packages.Package, error) {
+	pkgs, err := packages.Load(&packages.Config{Mode: mode}, pkgPath)
+	if err != nil {
+		return nil, err
+	}
+	if packages.PrintErrors(pkgs) > 0 {
+		return nil, fmt.Errorf("errors loading package %s", pkgPath)
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("package %s not found", pkgPath)
+	}
+	return pkgs[0], nil
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func parseConstraintComments(t *testing.T, fset *token.FileSet, src string) []*ast.CommentGroup {
+	t.Helper()
+	f, err := parser.ParseFile(fset, "tpl.go", src, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("parsing: %v", err)
+	}
+	return f.Comments
+}
+
+func TestParseConstraints_Malformed(t *testing.T) {
+	fset := token.NewFileSet()
+	comments := parseConstraintComments(t, fset, `package tpl
+
+// gogen:constraint _typeValue_
+type _typeValue_ int
+`)
+	if _, err := parseConstraints(fset, comments); err == nil {
+		t.Fatal("expected an error for a directive missing its kind")
+	}
+}
+
+func TestParseConstraints_Valid(t *testing.T) {
+	fset := token.NewFileSet()
+	comments := parseConstraintComments(t, fset, `package tpl
+
+// gogen:constraint _typeKey_ comparable
+// gogen:constraint _typeValue_ implements io.Reader
+type _typeValue_ int
+`)
+	cs, err := parseConstraints(fset, comments)
+	if err != nil {
+		t.Fatalf("parseConstraints: %v", err)
+	}
+	if len(cs) != 2 {
+		t.Fatalf("got %d constraints, want 2", len(cs))
+	}
+	if cs[0].kind != "comparable" || cs[1].kind != "implements" || cs[1].iface != "io.Reader" {
+		t.Fatalf("unexpected constraints: %+v", cs)
+	}
+}
+
+func TestCheckConstraints_NumericViolation(t *testing.T) {
+	fset := token.NewFileSet()
+	cs := []constraint{{placeholder: "_typeValue_", kind: "numeric"}}
+	if err := checkConstraints(fset, cs, map[string]string{"_typeValue_": "string"}); err == nil {
+		t.Fatal("expected a violation for string not being numeric")
+	}
+}
+
+func TestCheckConstraints_NumericSatisfied(t *testing.T) {
+	fset := token.NewFileSet()
+	cs := []constraint{{placeholder: "_typeValue_", kind: "numeric"}}
+	if err := checkConstraints(fset, cs, map[string]string{"_typeValue_": "int"}); err != nil {
+		t.Fatalf("expected int to satisfy numeric: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"go/build"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// defaultGOOS and defaultGOARCH are the platform matrix gogen scans by
+// default when collecting a template package's files, so that
+// platform-specific variants (_linux.go, _amd64.go, "// +build"-gated
+// files) are all instantiated, not just the ones visible under the host's
+// own GOOS/GOARCH.
+var (
+	defaultGOOS   = []string{"linux", "darwin", "windows"}
+	defaultGOARCH = []string{"386", "amd64", "arm", "arm64"}
+)
+
+// buildContexts returns the *build.Context values to scan a template package
+// with. goos/goarch restrict the default matrix to the given values (nil
+// means use the default list); contexts, if non-empty, is a list of
+// "GOOS/GOARCH" pairs that replaces the matrix entirely. Every entry is
+// expanded into a CgoEnabled and a non-CgoEnabled context.
+func buildContexts(goos, goarch, contexts []string) []*build.Context {
+	var ctxs []*build.Context
+
+	if len(contexts) > 0 {
+		for _, c := range contexts {
+			p := strings.SplitN(c, "/", 2)
+			if len(p) != 2 {
+				log.Fatalf("invalid -contexts entry %q, want GOOS/GOARCH", c)
+			}
+			ctxs = append(ctxs, newContext(p[0], p[1], false), newContext(p[0], p[1], true))
+		}
+		return ctxs
+	}
+
+	if len(goos) == 0 {
+		goos = defaultGOOS
+	}
+	if len(goarch) == 0 {
+		goarch = defaultGOARCH
+	}
+
+	for _, os := range goos {
+		for _, arch := range goarch {
+			ctxs = append(ctxs, newContext(os, arch, false), newContext(os, arch, true))
+		}
+	}
+	return ctxs
+}
+
+func newContext(goos, goarch string, cgoEnabled bool) *build.Context {
+	ctx := build.Default
+	ctx.GOOS = goos
+	ctx.GOARCH = goarch
+	ctx.CgoEnabled = cgoEnabled
+	return &ctx
+}
+
+// collectFiles scans the template package named arg (resolved relative to
+// wd) under every build.Context in the configured matrix and returns the
+// union of GoFiles, CgoFiles, TestGoFiles and XTestGoFiles across all of
+// them, as absolute paths deduplicated across contexts. goos, goarch and
+// contexts are comma-separated flag values; see buildContexts. Returns an
+// error if arg doesn't resolve to any files under any context in the
+// matrix.
+func collectFiles(arg, wd, goos, goarch, contexts string) ([]string, error) {
+	ctxs := buildContexts(splitList(goos), splitList(goarch), splitList(contexts))
+
+	seen := make(map[string]bool)
+	var files []string
+	var firstErr error
+	for _, ctx := range ctxs {
+		pack, err := ctx.Import(arg, wd, 0)
+		if err != nil {
+			// No files match this particular GOOS/GOARCH/CgoEnabled
+			// combination; try the next context. Only fail if none of
+			// them produced anything.
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		var names []string
+		names = append(names, pack.GoFiles...)
+		names = append(names, pack.CgoFiles...)
+		names = append(names, pack.TestGoFiles...)
+		names = append(names, pack.XTestGoFiles...)
+
+		for _, name := range names {
+			abs := filepath.Join(pack.Dir, name)
+			if seen[abs] {
+				continue
+			}
+			seen[abs] = true
+			files = append(files, abs)
+		}
+	}
+
+	if len(files) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, fmt.Errorf("%s: no Go files found under any configured GOOS/GOARCH", arg)
+	}
+	return files, nil
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestSplitList(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"linux", []string{"linux"}},
+		{"linux,darwin", []string{"linux", "darwin"}},
+	}
+	for _, c := range cases {
+		got := splitList(c.in)
+		if len(got) != len(c.want) {
+			t.Errorf("splitList(%q) = %v, want %v", c.in, got, c.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Errorf("splitList(%q) = %v, want %v", c.in, got, c.want)
+				break
+			}
+		}
+	}
+}
+
+func TestCollectFiles_NoMatch(t *testing.T) {
+	if _, err := collectFiles("no/such/package", t.TempDir(), "", "", ""); err == nil {
+		t.Fatal("expected an error for a package with no files under any context")
+	}
+}
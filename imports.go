@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+
+	"golang.org/x/tools/go/ast/astutil"
+)
+
+// rewriteQualifiedTypes upgrades placeholder idents substituted with a
+// package-qualified type (e.g. typeValue=github.com/foo/bar.Baz) from a
+// plain *ast.Ident into a *ast.SelectorExpr referencing the target
+// package, adding the necessary import. The template type declaration's
+// own name (e.g. `type _typeValue_ struct{...}`) is left as a bare ident
+// so mark/sweep can still find and remove it.
+func rewriteQualifiedTypes(fset *token.FileSet, fileNode *ast.File, mapping map[string]string, types []string) error {
+	qualified := make(map[string]string, len(types)) // placeholder -> pkgPath
+	bare := make(map[string]string, len(types))      // placeholder -> bare ident
+	for _, t := range types {
+		pkgPath, ident := splitQualified(mapping[t])
+		if pkgPath == "" {
+			continue
+		}
+		qualified[t] = pkgPath
+		bare[t] = ident
+	}
+	if len(qualified) == 0 {
+		return nil
+	}
+
+	// The package's declared name doesn't necessarily match the last
+	// segment of its import path (gopkg.in/yaml.v2 declares "yaml", not
+	// "yaml.v2"), so resolve it instead of guessing from the path.
+	pkgNames := make(map[string]string, len(qualified)) // pkgPath -> local identifier
+	for _, pkgPath := range qualified {
+		if _, ok := pkgNames[pkgPath]; ok {
+			continue
+		}
+		name, err := packageName(pkgPath)
+		if err != nil {
+			return fmt.Errorf("resolving package name for %q: %w", pkgPath, err)
+		}
+		pkgNames[pkgPath] = name
+		astutil.AddImport(fset, fileNode, pkgPath)
+	}
+
+	astutil.Apply(fileNode, func(c *astutil.Cursor) bool {
+		ident, ok := c.Node().(*ast.Ident)
+		if !ok {
+			return true
+		}
+		pkgPath, ok := qualified[ident.Name]
+		if !ok {
+			return true
+		}
+		if _, ok := c.Parent().(*ast.TypeSpec); ok && c.Name() == "Name" {
+			return true // the template's own declaration; mark/sweep removes it
+		}
+
+		c.Replace(&ast.SelectorExpr{X: ast.NewIdent(pkgNames[pkgPath]), Sel: ast.NewIdent(bare[ident.Name])})
+		return true
+	}, nil)
+	return nil
+}
+
+// bareMapping derives the identifier-only substitution map used for
+// identifier and comment text rewriting from a mapping whose values may be
+// package-qualified: "typeValue=github.com/foo/bar.Baz" substitutes the
+// bare "Baz" into code and comment text, with the package qualification
+// applied separately by rewriteQualifiedTypes as a *ast.SelectorExpr.
+func bareMapping(mapping map[string]string) map[string]string {
+	bare := make(map[string]string, len(mapping))
+	for k, v := range mapping {
+		if pkgPath, ident := splitQualified(v); pkgPath != "" {
+			bare[k] = ident
+			continue
+		}
+		bare[k] = v
+	}
+	return bare
+}
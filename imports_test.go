@@ -0,0 +1,30 @@
+package main
+
+import "testing"
+
+func TestBareMapping(t *testing.T) {
+	mapping := map[string]string{
+		"_typeValue_": "gopkg.in/yaml.v2.MapSlice",
+		"_typeKey_":   "int",
+	}
+	bare := bareMapping(mapping)
+	if bare["_typeValue_"] != "MapSlice" {
+		t.Errorf("bare[_typeValue_] = %q, want MapSlice", bare["_typeValue_"])
+	}
+	if bare["_typeKey_"] != "int" {
+		t.Errorf("bare[_typeKey_] = %q, want int", bare["_typeKey_"])
+	}
+}
+
+// TestRewriteQualifiedTypes_RealPackageName guards against regressing to
+// path.Base(pkgPath) for the import qualifier: gopkg.in/yaml.v2 declares
+// package "yaml", not "yaml.v2".
+func TestRewriteQualifiedTypes_RealPackageName(t *testing.T) {
+	name, err := packageName("gopkg.in/yaml.v2")
+	if err != nil {
+		t.Fatalf("packageName: %v", err)
+	}
+	if name != "yaml" {
+		t.Fatalf("packageName(gopkg.in/yaml.v2) = %q, want yaml", name)
+	}
+}
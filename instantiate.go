@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"os"
+	"path/filepath"
+)
+
+// instantiateOptions configures an instantiatePackage call: the
+// placeholder keys and whether to type-check are always needed, while
+// OutDir and PackageName are only set by -manifest entries that ask for a
+// dedicated output directory or a renamed package clause.
+type instantiateOptions struct {
+	Types       []string // placeholder keys, in output-file-name order
+	Typecheck   bool
+	OutDir      string // if set, write the instantiated files here instead of beside the template
+	PackageName string // if set, rewrite the output files' package clause
+}
+
+// rewrittenFile is the result of running the template pipeline over a
+// single source file, before it is type-checked and written out.
+type rewrittenFile struct {
+	src      string
+	node     *ast.File
+	replaced map[string]string
+}
+
+// instantiatePackage runs the template pipeline over every file in files
+// and, unless disabled, type-checks them together as a single package. A
+// mapping violation or malformed constraint in any one file fails the
+// whole package.
+func instantiatePackage(files []string, mapping map[string]string, opts instantiateOptions) error {
+	fset := token.NewFileSet()
+
+	rewritten := make([]*rewrittenFile, 0, len(files))
+	for _, file := range files {
+		rf, err := rewriteFile(fset, file, mapping, opts)
+		if err != nil {
+			// rewriteFile's errors already carry file:line:col via fset, or
+			// (for rewriteQualifiedTypes) the file path directly -- don't
+			// prefix the path a second time on top of that.
+			return err
+		}
+		rewritten = append(rewritten, rf)
+	}
+
+	if opts.Typecheck {
+		nodes := make([]*ast.File, len(rewritten))
+		for i, rf := range rewritten {
+			nodes[i] = rf.node
+		}
+		if err := typecheck(fset, nodes); err != nil {
+			return err
+		}
+	}
+
+	for _, rf := range rewritten {
+		if err := writeInstantiated(fset, rf, opts); err != nil {
+			return fmt.Errorf("%s: %w", rf.src, err)
+		}
+	}
+	return nil
+}
+
+// rewriteFile runs the constraint-validation, comment, and mark/sweep
+// pipeline over a single template file, without type-checking or writing
+// it -- that happens once per package in instantiatePackage.
+func rewriteFile(fset *token.FileSet, file string, mapping map[string]string, opts instantiateOptions) (*rewrittenFile, error) {
+	fileNode, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	constraints, err := parseConstraints(fset, fileNode.Comments)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkConstraints(fset, constraints, mapping); err != nil {
+		return nil, err
+	}
+
+	// mapping values may be package-qualified (typeValue=github.com/foo/bar.Baz);
+	// promote exact-match placeholder idents in type position to a
+	// *ast.SelectorExpr before mark/sweep do their plain-text substitution
+	// with the bare identifier.
+	if err := rewriteQualifiedTypes(fset, fileNode, mapping, opts.Types); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	bare := bareMapping(mapping)
+
+	cmap := ast.NewCommentMap(fset, fileNode, fileNode.Comments)
+	rewriteComments(cmap, bare)
+
+	replaced := mark(fileNode, bare)
+	// Placeholders substituted with a package-qualified type were already
+	// turned into a *ast.SelectorExpr by rewriteQualifiedTypes, so mark
+	// never saw them as a plain ident and didn't record a replacement --
+	// fill those in so outputFileName still picks up the substituted name.
+	for _, t := range opts.Types {
+		if replaced[t] != "" {
+			continue
+		}
+		if pkgPath, ident := splitQualified(mapping[t]); pkgPath != "" {
+			replaced[t] = ident
+		}
+	}
+	sweep(fileNode, bare)
+	// ast.Print(fset, fileNode)
+
+	// Drop comments attached only to removed nodes (e.g. the doc comment on
+	// the template TypeSpec) and keep the rest in sync with the surviving
+	// AST so godoc stays intact.
+	fileNode.Comments = cmap.Filter(fileNode).Comments()
+
+	pkgName := opts.PackageName
+	if pkgName == "" && opts.OutDir != "" {
+		pkgName = filepath.Base(opts.OutDir)
+	}
+	if pkgName != "" {
+		fileNode.Name.Name = pkgName
+	}
+
+	return &rewrittenFile{src: file, node: fileNode, replaced: replaced}, nil
+}
+
+func writeInstantiated(fset *token.FileSet, rf *rewrittenFile, opts instantiateOptions) error {
+	out := outputFileName(rf.src, rf.replaced, opts.Types)
+	if opts.OutDir != "" {
+		if err := os.MkdirAll(opts.OutDir, 0o755); err != nil {
+			return err
+		}
+		out = filepath.Join(opts.OutDir, out)
+	}
+
+	f, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return printer.Fprint(f, fset, rf.node)
+}
@@ -3,10 +3,6 @@ package main
 import (
 	"flag"
 	"go/ast"
-	"go/build"
-	"go/parser"
-	"go/printer"
-	"go/token"
 	"log"
 	"os"
 	"path/filepath"
@@ -49,6 +45,23 @@ func mark(fileNode *ast.File, mapping map[string]string) map[string]string {
 	return res
 }
 
+// rewriteComments replaces marker tokens found in comment text (doc comments,
+// //go:generate directives, prose referring to a placeholder) using the same
+// mapping applied to identifiers by mark. Comment text without a mapped token
+// is left untouched, since prose can incidentally match the marker pattern.
+func rewriteComments(cmap ast.CommentMap, mapping map[string]string) {
+	for _, group := range cmap.Comments() {
+		for _, c := range group.List {
+			c.Text = re.ReplaceAllStringFunc(c.Text, func(name string) string {
+				if r := mapping[name]; r != "" {
+					return r
+				}
+				return name
+			})
+		}
+	}
+}
+
 // Iterate over AST nodes and remove marked declarations.
 func sweep(fileNode *ast.File, mapping map[string]string) {
 	decls := make([]ast.Decl, 0, len(fileNode.Decls))
@@ -104,8 +117,19 @@ func main() {
 		log.Printf("%s typeKey=int typeValue=string github.com/AlekSi/gogen-library/map",
 			os.Args[0])
 	}
+	goosFlag := flag.String("goos", "", "comma-separated GOOS values to scan a template package under (default: "+strings.Join(defaultGOOS, ",")+")")
+	goarchFlag := flag.String("goarch", "", "comma-separated GOARCH values to scan a template package under (default: "+strings.Join(defaultGOARCH, ",")+")")
+	contextsFlag := flag.String("contexts", "", "comma-separated GOOS/GOARCH pairs to scan a template package under, overriding -goos/-goarch")
+	typecheckFlag := flag.Bool("typecheck", true, "type-check the instantiated file with go/types before writing it out")
+	manifestFlag := flag.String("manifest", "", "process every instantiation listed in this manifest file instead of the command-line mappings/arguments")
+	outFlag := flag.String("out", "", "write instantiated files into this directory instead of beside the template; also rewrites the package clause to the directory's base name")
 	flag.Parse()
 
+	if *manifestFlag != "" {
+		runManifest(*manifestFlag, *typecheckFlag)
+		return
+	}
+
 	mapping := make(map[string]string)
 	var types []string
 	args := flag.Args()
@@ -142,39 +166,15 @@ func main() {
 			if err != nil {
 				log.Fatal(err)
 			}
-			pack, err := build.Import(arg, wd, 0)
+			files, err = collectFiles(arg, wd, *goosFlag, *goarchFlag, *contextsFlag)
 			if err != nil {
 				log.Fatal(err)
 			}
-			files = append(files, pack.GoFiles...)
-			files = append(files, pack.CgoFiles...)
-			files = append(files, pack.TestGoFiles...)
-			files = append(files, pack.XTestGoFiles...)
-			for i, file := range files {
-				files[i] = filepath.Join(pack.Dir, file)
-			}
 		}
 
-		for _, file := range files {
-			log.SetPrefix(file + ": ")
-
-			fset := token.NewFileSet()
-			fileNode, err := parser.ParseFile(fset, file, nil, parser.ParseComments)
-			if err != nil {
-				log.Fatal(err)
-			}
-
-			replaced := mark(fileNode, mapping)
-			sweep(fileNode, mapping)
-			// ast.Print(fset, fileNode)
-
-			out := outputFileName(file, replaced, types)
-			f, err := os.Create(out)
-			if err != nil {
-				log.Fatal(err)
-			}
-			printer.Fprint(f, fset, fileNode)
-			f.Close()
+		opts := instantiateOptions{Types: types, Typecheck: *typecheckFlag, OutDir: *outFlag}
+		if err := instantiatePackage(files, mapping, opts); err != nil {
+			log.Fatal(err)
 		}
 	}
 }
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v2"
+)
+
+// manifestEntry describes a single template instantiation inside a
+// -manifest file: a source package or file, the mappings to substitute,
+// and where to write the result.
+type manifestEntry struct {
+	Source   string            `yaml:"source"`   // package import path, or a single .go file
+	Mappings map[string]string `yaml:"mappings"` // typeKey: int, typeValue: string, ...
+	Out      string            `yaml:"out"`      // optional output directory
+	Package  string            `yaml:"package"`  // optional: rename directive for the output package clause
+	GOOS     string            `yaml:"goos"`     // optional: comma-separated GOOS values, see -goos
+	GOARCH   string            `yaml:"goarch"`   // optional: comma-separated GOARCH values, see -goarch
+	Contexts string            `yaml:"contexts"` // optional: comma-separated GOOS/GOARCH pairs, see -contexts
+}
+
+// manifest is the top-level document read from -manifest: a flat list of
+// instantiations to process in one run.
+type manifest struct {
+	Entries []manifestEntry `yaml:"entries"`
+}
+
+func loadManifest(path string) (*manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing manifest %s: %w", path, err)
+	}
+	return &m, nil
+}
+
+// manifestWorkers bounds how many manifest entries runManifest processes
+// concurrently.
+const manifestWorkers = 8
+
+// runManifest processes every entry of a -manifest file with a small
+// worker pool. A failing entry doesn't abort the others -- every entry
+// runs to completion and every failure is reported together at the end.
+func runManifest(path string, typecheckFlag bool) {
+	m, err := loadManifest(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	errs := processManifestEntries(m.Entries, typecheckFlag)
+	if len(errs) == 0 {
+		return
+	}
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	log.Fatalf("%d of %d entries failed:\n\t%s", len(errs), len(m.Entries), strings.Join(msgs, "\n\t"))
+}
+
+// processManifestEntries runs every entry through processManifestEntry with
+// a small worker pool and returns every entry's error, instead of aborting
+// the whole batch on the first one to fail.
+func processManifestEntries(entries []manifestEntry, typecheckFlag bool) []error {
+	pending := make(chan manifestEntry)
+	failed := make(chan error, len(entries))
+
+	var wg sync.WaitGroup
+	for i := 0; i < manifestWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for e := range pending {
+				if err := processManifestEntry(e, typecheckFlag); err != nil {
+					failed <- err
+				}
+			}
+		}()
+	}
+	for _, e := range entries {
+		pending <- e
+	}
+	close(pending)
+	wg.Wait()
+	close(failed)
+
+	var errs []error
+	for err := range failed {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// processManifestEntry resolves a manifest entry's source to its package's
+// files across the same GOOS/GOARCH/Cgo matrix collectFiles scans for a
+// command-line package argument (or treats it as a single file), and
+// instantiates them.
+func processManifestEntry(e manifestEntry, typecheckFlag bool) error {
+	mapping, types := manifestMapping(e.Mappings)
+
+	var files []string
+	if strings.HasSuffix(e.Source, ".go") {
+		files = []string{e.Source}
+	} else {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		files, err = collectFiles(e.Source, wd, e.GOOS, e.GOARCH, e.Contexts)
+		if err != nil {
+			return fmt.Errorf("%s: %w", e.Source, err)
+		}
+	}
+
+	opts := instantiateOptions{Types: types, Typecheck: typecheckFlag, OutDir: e.Out, PackageName: e.Package}
+	if err := instantiatePackage(files, mapping, opts); err != nil {
+		return fmt.Errorf("%s: %w", e.Source, err)
+	}
+	return nil
+}
+
+// manifestMapping expands a manifest entry's mappings the same way
+// command-line typeKey=value arguments are expanded -- both the literal and
+// title-cased forms are registered -- and returns the placeholder keys in a
+// stable, sorted order for deterministic output file names.
+func manifestMapping(raw map[string]string) (map[string]string, []string) {
+	mapping := make(map[string]string, len(raw)*2)
+	types := make([]string, 0, len(raw))
+	for k := range raw {
+		types = append(types, "_"+k+"_")
+	}
+	sort.Strings(types)
+
+	for k, v := range raw {
+		mapping["_"+k+"_"] = v
+		mapping["_"+strings.Title(k)+"_"] = strings.Title(v)
+	}
+	return mapping, types
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func writeManifestSrc(t *testing.T, dir, name, src string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+		t.Fatalf("writing %s: %v", name, err)
+	}
+}
+
+// TestProcessManifestEntry_CollectsPlatformFiles guards against regressing
+// to build.Import+GoFiles, which silently drops platform-specific template
+// files like thing_windows.go from a manifest entry's source package.
+func TestProcessManifestEntry_CollectsPlatformFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeManifestSrc(t, dir, "common.go", "package tpl\n\ntype _typeValue_ int\n\nfunc F(v _typeValue_) { _ = v }\n")
+	writeManifestSrc(t, dir, "thing_windows.go", "package tpl\n\nfunc G(v _typeValue_) { _ = v }\n")
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	out := filepath.Join(dir, "out")
+	e := manifestEntry{Source: ".", Mappings: map[string]string{"typeValue": "int"}, Out: out}
+	if err := processManifestEntry(e, false); err != nil {
+		t.Fatalf("processManifestEntry: %v", err)
+	}
+
+	entries, err := os.ReadDir(out)
+	if err != nil {
+		t.Fatalf("reading out dir: %v", err)
+	}
+	var names []string
+	for _, f := range entries {
+		names = append(names, f.Name())
+	}
+	sort.Strings(names)
+
+	want := []string{"common_int.go", "thing_windows_int.go"}
+	if len(names) != len(want) {
+		t.Fatalf("got output files %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("got output files %v, want %v", names, want)
+		}
+	}
+}
+
+// TestProcessManifestEntries_OneFailureDoesNotAbortOthers guards against
+// regressing to log.Fatal inside a worker goroutine, which kills the whole
+// batch -- including entries already in flight -- on the first failure.
+func TestProcessManifestEntries_OneFailureDoesNotAbortOthers(t *testing.T) {
+	dir := t.TempDir()
+	writeManifestSrc(t, dir, "good.go", "package tpl\n\ntype _typeValue_ int\n\nfunc F(v _typeValue_) { _ = v }\n")
+
+	out := filepath.Join(dir, "out")
+	entries := []manifestEntry{
+		{Source: "no/such/package", Mappings: map[string]string{"typeValue": "int"}},
+		{Source: filepath.Join(dir, "good.go"), Mappings: map[string]string{"typeValue": "int"}, Out: out},
+	}
+
+	errs := processManifestEntries(entries, false)
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1: %v", len(errs), errs)
+	}
+
+	if _, err := os.Stat(filepath.Join(out, "good_int.go")); err != nil {
+		t.Fatalf("good entry's output was not written: %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// typecheck runs go/types over every rewritten file of a package together,
+// so unexported helpers shared across files still resolve. Reported
+// errors carry positions from fset, i.e. from the original template
+// source.
+func typecheck(fset *token.FileSet, files []*ast.File) error {
+	var errs []string
+	conf := types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+		Error: func(err error) {
+			errs = append(errs, err.Error())
+		},
+	}
+
+	var pkgName string
+	if len(files) > 0 {
+		pkgName = files[0].Name.Name
+	}
+	conf.Check(pkgName, fset, files, nil)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("instantiation does not type-check:\n\t%s", strings.Join(errs, "\n\t"))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"testing"
+)
+
+func parseSrc(t *testing.T, fset *token.FileSet, name, src string) *ast.File {
+	t.Helper()
+	f, err := parser.ParseFile(fset, name, src, 0)
+	if err != nil {
+		t.Fatalf("parsing %s: %v", name, err)
+	}
+	return f
+}
+
+func TestTypecheck_CrossFileHelper(t *testing.T) {
+	fset := token.NewFileSet()
+	a := parseSrc(t, fset, "a.go", `package tpl
+
+func UseA(v int) int { return helper(v) }
+`)
+	b := parseSrc(t, fset, "b.go", `package tpl
+
+func helper(v int) int { return v }
+`)
+
+	if err := typecheck(fset, []*ast.File{a, b}); err != nil {
+		t.Fatalf("typecheck() across files sharing a helper: %v", err)
+	}
+}
+
+func TestTypecheck_ReportsError(t *testing.T) {
+	fset := token.NewFileSet()
+	f := parseSrc(t, fset, "a.go", `package tpl
+
+func F() int { return "not an int" }
+`)
+	if err := typecheck(fset, []*ast.File{f}); err == nil {
+		t.Fatal("expected a type error, got nil")
+	}
+}